@@ -0,0 +1,132 @@
+/*
+Copyright 2016 Stanislav Liberman
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package arrowterm publishes Apache Arrow IPC record batches into an Aeron
+// term, one IPC message per frame, for analytics pipelines that already
+// speak Arrow.
+//
+// AppendArrowRecord is NOT zero-copy: arrow-go's public ipc.Writer only
+// serializes to an io.Writer, it does not expose a way to compute a
+// message's encoded length up front or to serialize directly into a
+// caller-owned buffer, and Aeron's Claim needs the frame length before
+// reserving space. AppendArrowRecord therefore serializes each message into
+// a scratch buffer and then copies it once more into the claimed term
+// region - two copies per message, not zero. A caller chasing a genuine
+// zero-copy publish path should use term.Appender.Claim directly instead of
+// this package. A true single-copy arrowterm writer would need to
+// hand-construct IPC messages against arrow-go's unexported
+// flatbuffer/payload types. The subscriber side (Reader) has no such
+// constraint and is genuinely zero-copy: it wraps the term buffer's own
+// memory for the record's column buffers instead of copying them.
+package arrowterm
+
+import (
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+
+	"github.com/lirm/aeron-go/aeron/logbuffer"
+	"github.com/lirm/aeron-go/aeron/logbuffer/term"
+)
+
+// messageSplitter captures each Write call made by an ipc.Writer as its own
+// IPC message. ipc.Writer emits the schema message and a session's first
+// record batch message as two separate low-level Write calls within one
+// high-level Write(rec), so splitting on those call boundaries is enough to
+// recover individual messages without parsing the flatbuffer payloads -
+// which matters here because Reader.ReadRecord expects exactly one IPC
+// message per frame.
+type messageSplitter struct {
+	messages [][]byte
+}
+
+func (s *messageSplitter) Write(p []byte) (int, error) {
+	msg := make([]byte, len(p))
+	copy(msg, p)
+	s.messages = append(s.messages, msg)
+	return len(p), nil
+}
+
+func (s *messageSplitter) reset() {
+	s.messages = s.messages[:0]
+}
+
+// Appender publishes arrow.Record values into a term via the wrapped
+// term.Appender.
+type Appender struct {
+	termAppender *term.Appender
+	schema       *arrow.Schema
+	writer       *ipc.Writer
+	split        messageSplitter
+}
+
+// NewAppender wraps termAppender for publishing Arrow record batches.
+func NewAppender(termAppender *term.Appender) *Appender {
+	return &Appender{termAppender: termAppender}
+}
+
+// AppendArrowRecord encodes rec as one or more IPC messages - a schema
+// message the first time a session sees rec's schema, always followed by
+// rec's record batch message - and publishes each as its own frame, in
+// order, via Claim. Each message is copied twice (once into a scratch
+// buffer by ipc.Writer, once more into the claimed term region) before
+// publication; see the package doc for why, and use term.Appender.Claim
+// directly if eliminating the copy matters more than speaking Arrow IPC.
+func (a *Appender) AppendArrowRecord(result *term.AppenderResult, rec arrow.Record) error {
+	if a.schema == nil || !a.schema.Equal(rec.Schema()) {
+		a.schema = rec.Schema()
+		a.writer = ipc.NewWriter(&a.split, ipc.WithSchema(a.schema))
+	}
+
+	a.split.reset()
+	if err := a.writer.Write(rec); err != nil {
+		return err
+	}
+
+	for _, msg := range a.split.messages {
+		if err := a.publishFrame(result, msg); err != nil {
+			return err
+		}
+		if tripped(result) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (a *Appender) publishFrame(result *term.AppenderResult, msg []byte) error {
+	var claim logbuffer.Claim
+	a.termAppender.Claim(result, int32(len(msg)), &claim)
+	if tripped(result) {
+		return nil
+	}
+
+	claim.Buffer().PutBytesArray(claim.Offset(), msg)
+	claim.Commit()
+	return nil
+}
+
+func tripped(result *term.AppenderResult) bool {
+	return result.TermOffset() == term.AppenderTripped || result.TermOffset() == term.AppenderFailed
+}
+
+// Close releases the underlying ipc.Writer's resources. It does not close
+// the wrapped term.Appender.
+func (a *Appender) Close() error {
+	if a.writer == nil {
+		return nil
+	}
+	return a.writer.Close()
+}
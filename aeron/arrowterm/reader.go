@@ -0,0 +1,81 @@
+/*
+Copyright 2016 Stanislav Liberman
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package arrowterm
+
+import (
+	"unsafe"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+
+	"github.com/lirm/aeron-go/aeron/atomic"
+)
+
+// Reader reconstructs arrow.Record values published by Appender, wrapping
+// the term buffer's own memory for the record's column buffers rather than
+// copying it. The returned records are only valid while the underlying term
+// buffer segment has not been recycled; callers that need to retain a
+// record past the handling of the current fragment must copy it themselves
+// (e.g. via array.NewRecord with cloned buffers).
+type Reader struct {
+	mem    memory.Allocator
+	schema *arrow.Schema
+}
+
+// NewReader creates a Reader. mem is used only for decoder bookkeeping, not
+// for the record's column buffers, which alias term buffer memory directly.
+func NewReader(mem memory.Allocator) *Reader {
+	if mem == nil {
+		mem = memory.NewGoAllocator()
+	}
+	return &Reader{mem: mem}
+}
+
+// ReadRecord parses the single IPC message found in buffer at
+// [offset, offset+length) - Appender publishes exactly one IPC message per
+// frame - and returns the resulting arrow.Record. A frame carrying a schema
+// message caches the schema for every subsequent record batch message and
+// returns a nil record; callers should skip those rather than treat them as
+// an error.
+func (r *Reader) ReadRecord(buffer *atomic.Buffer, offset, length int32) (arrow.Record, error) {
+	body := memory.NewBufferBytes(zeroCopyBytes(buffer, offset, length))
+	msg := ipc.NewMessage(body)
+	defer msg.Release()
+
+	switch msg.Type() {
+	case ipc.MessageSchema:
+		schema, err := ipc.MessageToSchema(msg, r.mem)
+		if err != nil {
+			return nil, err
+		}
+		r.schema = schema
+		return nil, nil
+	case ipc.MessageRecordBatch:
+		return ipc.ReadRecordBatchWithRuntime(msg, r.schema, nil, r.mem)
+	default:
+		return nil, nil
+	}
+}
+
+// zeroCopyBytes returns a []byte view of buffer's [offset, offset+length)
+// region without copying, relying on the term buffer outliving the returned
+// slice the same way termBuffer.Ptr() is used elsewhere in this package.
+func zeroCopyBytes(buffer *atomic.Buffer, offset, length int32) []byte {
+	ptr := unsafe.Pointer(uintptr(buffer.Ptr()) + uintptr(offset))
+	return unsafe.Slice((*byte)(ptr), length)
+}
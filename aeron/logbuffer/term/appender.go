@@ -75,6 +75,7 @@ type Appender struct {
 	termBuffer   *atomic.Buffer
 	tailCounter  flyweight.Int64Field
 	headerWriter headerWriter
+	lockManager  TailLockManager
 }
 
 // AppenderResult is a helper structure for a zero-copy tuple return. Can likely be done with Go's tuple return
@@ -102,6 +103,7 @@ func MakeAppender(logBuffers *logbuffer.LogBuffers, partitionIndex int) *Appende
 
 	header := logBuffers.Meta().DefaultFrameHeader.Get()
 	appender.headerWriter.fill(header)
+	appender.lockManager = NoopTailLockManager{}
 
 	return appender
 }
@@ -112,7 +114,32 @@ func (appender *Appender) RawTail() int64 {
 }
 
 func (appender *Appender) getAndAddRawTail(alignedLength int32) int64 {
-	return appender.tailCounter.GetAndAddInt64(int64(alignedLength))
+	if _, noop := appender.lockManager.(NoopTailLockManager); noop {
+		return appender.tailCounter.GetAndAddInt64(int64(alignedLength))
+	}
+
+	streamID, sessionID := appender.headerWriter.streamID, appender.headerWriter.sessionID
+	if err := appender.lockManager.Lock(streamID, sessionID); err != nil {
+		// A lock we couldn't take still leaves the CAS below as the only
+		// thing protecting tailCounter, so fall back to it rather than
+		// stalling the publisher.
+		return appender.tailCounter.GetAndAddInt64(int64(alignedLength))
+	}
+	defer appender.lockManager.Unlock(streamID, sessionID)
+
+	// With a real TailLockManager held, every writer sharing this log
+	// buffer file is already serialized on (streamID, sessionID), so the
+	// read-then-write below needs no atomic RMW of its own - the lock is
+	// what makes this safe across processes, not the CAS.
+	rawTail := appender.tailCounter.Get()
+	appender.tailCounter.Set(rawTail + int64(alignedLength))
+	return rawTail
+}
+
+// Close releases any resources held by the Appender's TailLockManager. It is
+// a no-op for the default NoopTailLockManager.
+func (appender *Appender) Close() error {
+	return appender.lockManager.Close()
 }
 
 // Claim is the interface for using Buffer Claims for zero copy sends
@@ -220,6 +247,355 @@ func (appender *Appender) AppendFragmentedMessage(result *AppenderResult,
 	}
 }
 
+// AppendUnfragmentedMessageWithTrace behaves like AppendUnfragmentedMessage
+// but additionally invokes traceContextSupplier and sets traceContextFlag on
+// the frame, so a subscriber can recover the trace context via
+// IsTraceContext/DecodeTraceContext instead of treating the reserved value as
+// application data. If reservedValueSupplier is also given, its result is
+// written first and traceContextSupplier's result then overwrites it.
+func (appender *Appender) AppendUnfragmentedMessageWithTrace(result *AppenderResult,
+	srcBuffer *atomic.Buffer, srcOffset int32, length int32,
+	reservedValueSupplier ReservedValueSupplier, traceContextSupplier TraceContextSupplier) {
+
+	frameLength := length + logbuffer.DataFrameHeader.Length
+	alignedLength := util.AlignInt32(frameLength, logbuffer.FrameAlignment)
+	rawTail := appender.getAndAddRawTail(alignedLength)
+	termOffset := rawTail & 0xFFFFFFFF
+
+	termLength := appender.termBuffer.Capacity()
+
+	result.termID = logbuffer.TermID(rawTail)
+	result.termOffset = termOffset + int64(alignedLength)
+	if result.termOffset > int64(termLength) {
+		result.termOffset = handleEndOfLogCondition(result.termID, appender.termBuffer, int32(termOffset),
+			&appender.headerWriter, termLength)
+		return
+	}
+
+	offset := int32(termOffset)
+	appender.headerWriter.write(appender.termBuffer, offset, frameLength, result.termID)
+	appender.termBuffer.PutBytes(offset+logbuffer.DataFrameHeader.Length, srcBuffer, srcOffset, length)
+
+	if nil != reservedValueSupplier {
+		reservedValue := reservedValueSupplier(appender.termBuffer, offset, frameLength)
+		appender.termBuffer.PutInt64(offset+logbuffer.DataFrameHeader.ReservedValueFieldOffset, reservedValue)
+	}
+
+	if nil != traceContextSupplier {
+		reservedValue := traceContextSupplier(appender.termBuffer, offset, frameLength)
+		appender.termBuffer.PutInt64(offset+logbuffer.DataFrameHeader.ReservedValueFieldOffset, reservedValue)
+		logbuffer.FrameFlags(appender.termBuffer, offset, unfragmented|traceContextFlag)
+	}
+
+	logbuffer.FrameLengthOrdered(appender.termBuffer, offset, frameLength)
+}
+
+// AppendFragmentedMessageWithTrace behaves like AppendFragmentedMessage but
+// additionally invokes traceContextSupplier for every fragment and stamps
+// each one with traceContextFlag and the supplier's result. A supplier that
+// ignores its arguments, as the otel and zipkin adapters' suppliers do, thus
+// gives every fragment the same encoded reserved value, so the trace context
+// survives reassembly regardless of which fragment a subscriber inspects
+// first.
+func (appender *Appender) AppendFragmentedMessageWithTrace(result *AppenderResult,
+	srcBuffer *atomic.Buffer, srcOffset int32, length int32, maxPayloadLength int32,
+	reservedValueSupplier ReservedValueSupplier, traceContextSupplier TraceContextSupplier) {
+
+	numMaxPayloads := length / maxPayloadLength
+	remainingPayload := length % maxPayloadLength
+	var lastFrameLength int32
+	if remainingPayload > 0 {
+		lastFrameLength = util.AlignInt32(remainingPayload+logbuffer.DataFrameHeader.Length, logbuffer.FrameAlignment)
+	}
+	requiredLength := (numMaxPayloads * (maxPayloadLength + logbuffer.DataFrameHeader.Length)) + lastFrameLength
+	rawTail := appender.getAndAddRawTail(requiredLength)
+	termOffset := rawTail & 0xFFFFFFFF
+
+	termLength := appender.termBuffer.Capacity()
+
+	result.termID = logbuffer.TermID(rawTail)
+	result.termOffset = termOffset + int64(requiredLength)
+	if result.termOffset > int64(termLength) {
+		result.termOffset = handleEndOfLogCondition(result.termID, appender.termBuffer, int32(termOffset),
+			&appender.headerWriter, termLength)
+		return
+	}
+
+	flags := beginFrag
+	remaining := length
+	offset := int32(termOffset)
+
+	for remaining > 0 {
+		bytesToWrite := int32(math.Min(float64(remaining), float64(maxPayloadLength)))
+		frameLength := bytesToWrite + logbuffer.DataFrameHeader.Length
+		alignedLength := util.AlignInt32(frameLength, logbuffer.FrameAlignment)
+
+		appender.headerWriter.write(appender.termBuffer, offset, frameLength, result.termID)
+		appender.termBuffer.PutBytes(
+			offset+logbuffer.DataFrameHeader.Length, srcBuffer, srcOffset+(length-remaining), bytesToWrite)
+
+		if remaining <= maxPayloadLength {
+			flags |= endFrag
+		}
+
+		if nil != traceContextSupplier {
+			flags |= traceContextFlag
+		}
+		logbuffer.FrameFlags(appender.termBuffer, offset, flags)
+
+		var reservedValue int64
+		if nil != reservedValueSupplier {
+			reservedValue = reservedValueSupplier(appender.termBuffer, offset, frameLength)
+		}
+		if nil != traceContextSupplier {
+			reservedValue = traceContextSupplier(appender.termBuffer, offset, frameLength)
+		}
+		appender.termBuffer.PutInt64(offset+logbuffer.DataFrameHeader.ReservedValueFieldOffset, reservedValue)
+
+		logbuffer.FrameLengthOrdered(appender.termBuffer, offset, frameLength)
+
+		flags = 0
+		offset += alignedLength
+		remaining -= bytesToWrite
+	}
+}
+
+// ClaimWithTrace behaves like Claim but additionally invokes
+// traceContextSupplier and stamps the claimed frame with traceContextFlag, so
+// the trace context is visible to a subscriber even though the payload is
+// written by the caller after Claim returns.
+func (appender *Appender) ClaimWithTrace(result *AppenderResult, length int32, claim *logbuffer.Claim,
+	traceContextSupplier TraceContextSupplier) {
+
+	frameLength := length + logbuffer.DataFrameHeader.Length
+	alignedLength := util.AlignInt32(frameLength, logbuffer.FrameAlignment)
+	rawTail := appender.getAndAddRawTail(alignedLength)
+	termOffset := rawTail & 0xFFFFFFFF
+
+	termLength := appender.termBuffer.Capacity()
+
+	result.termID = logbuffer.TermID(rawTail)
+	result.termOffset = termOffset + int64(alignedLength)
+	if result.termOffset > int64(termLength) {
+		result.termOffset = handleEndOfLogCondition(result.termID, appender.termBuffer, int32(termOffset),
+			&appender.headerWriter, termLength)
+		return
+	}
+
+	offset := int32(termOffset)
+	appender.headerWriter.write(appender.termBuffer, offset, frameLength, result.termID)
+
+	if nil != traceContextSupplier {
+		reservedValue := traceContextSupplier(appender.termBuffer, offset, frameLength)
+		appender.termBuffer.PutInt64(offset+logbuffer.DataFrameHeader.ReservedValueFieldOffset, reservedValue)
+		logbuffer.FrameFlags(appender.termBuffer, offset, unfragmented|traceContextFlag)
+	}
+
+	claim.Wrap(appender.termBuffer, offset, frameLength)
+}
+
+// AppendUnfragmentedMessageWithFullTrace behaves like
+// AppendUnfragmentedMessage but prepends a TraceHeaderLength-byte header
+// encoding traceCtx (see EncodeTraceHeader) to the payload and sets
+// fullTraceHeaderFlag, so a subscriber can recover the exact trace and span
+// ids a tracing backend recorded - unlike AppendUnfragmentedMessageWithTrace,
+// which only carries a lossy, one-way folded tag in the reserved value.
+func (appender *Appender) AppendUnfragmentedMessageWithFullTrace(result *AppenderResult,
+	srcBuffer *atomic.Buffer, srcOffset int32, length int32, traceCtx FullTraceContext,
+	reservedValueSupplier ReservedValueSupplier) {
+
+	header := EncodeTraceHeader(traceCtx)
+	frameLength := TraceHeaderLength + length + logbuffer.DataFrameHeader.Length
+	alignedLength := util.AlignInt32(frameLength, logbuffer.FrameAlignment)
+	rawTail := appender.getAndAddRawTail(alignedLength)
+	termOffset := rawTail & 0xFFFFFFFF
+
+	termLength := appender.termBuffer.Capacity()
+
+	result.termID = logbuffer.TermID(rawTail)
+	result.termOffset = termOffset + int64(alignedLength)
+	if result.termOffset > int64(termLength) {
+		result.termOffset = handleEndOfLogCondition(result.termID, appender.termBuffer, int32(termOffset),
+			&appender.headerWriter, termLength)
+		return
+	}
+
+	offset := int32(termOffset)
+	appender.headerWriter.write(appender.termBuffer, offset, frameLength, result.termID)
+	appender.termBuffer.PutBytesArray(offset+logbuffer.DataFrameHeader.Length, header[:])
+	appender.termBuffer.PutBytes(offset+logbuffer.DataFrameHeader.Length+TraceHeaderLength, srcBuffer, srcOffset, length)
+
+	if nil != reservedValueSupplier {
+		reservedValue := reservedValueSupplier(appender.termBuffer, offset, frameLength)
+		appender.termBuffer.PutInt64(offset+logbuffer.DataFrameHeader.ReservedValueFieldOffset, reservedValue)
+	}
+
+	logbuffer.FrameFlags(appender.termBuffer, offset, unfragmented|fullTraceHeaderFlag)
+	logbuffer.FrameLengthOrdered(appender.termBuffer, offset, frameLength)
+}
+
+// compressPayload runs codec over the srcBuffer slice, returning it
+// unmodified along with CodecNone if codec is nil or does not shrink the
+// payload.
+func compressPayload(srcBuffer *atomic.Buffer, srcOffset, length int32, codec CompressionCodec) (uint8, []byte, error) {
+	payload := srcBuffer.GetBytesArray(srcOffset, length)
+	if codec == nil {
+		return CodecNone, payload, nil
+	}
+
+	compressed, err := codec.Compress(nil, payload)
+	if err != nil {
+		return CodecNone, nil, err
+	}
+	if int32(len(compressed)) >= length {
+		return CodecNone, payload, nil
+	}
+
+	return codec.ID(), compressed, nil
+}
+
+// AppendUnfragmentedMessageCompressed compresses the payload with codec and
+// appends it as a single frame, falling back to an uncompressed frame with
+// CodecNone when compression does not shrink the payload (or codec is nil).
+// The codec id and original length are encoded into the frame's reserved
+// value, see EncodeCompressedReservedValue.
+func (appender *Appender) AppendUnfragmentedMessageCompressed(result *AppenderResult,
+	srcBuffer *atomic.Buffer, srcOffset int32, length int32, codec CompressionCodec) error {
+
+	codecID, payload, err := compressPayload(srcBuffer, srcOffset, length, codec)
+	if err != nil {
+		return err
+	}
+
+	payloadLength := int32(len(payload))
+	frameLength := payloadLength + logbuffer.DataFrameHeader.Length
+	alignedLength := util.AlignInt32(frameLength, logbuffer.FrameAlignment)
+	rawTail := appender.getAndAddRawTail(alignedLength)
+	termOffset := rawTail & 0xFFFFFFFF
+
+	termLength := appender.termBuffer.Capacity()
+
+	result.termID = logbuffer.TermID(rawTail)
+	result.termOffset = termOffset + int64(alignedLength)
+	if result.termOffset > int64(termLength) {
+		result.termOffset = handleEndOfLogCondition(result.termID, appender.termBuffer, int32(termOffset),
+			&appender.headerWriter, termLength)
+		return nil
+	}
+
+	offset := int32(termOffset)
+	appender.headerWriter.write(appender.termBuffer, offset, frameLength, result.termID)
+	appender.termBuffer.PutBytesArray(offset+logbuffer.DataFrameHeader.Length, payload)
+	appender.termBuffer.PutInt64(offset+logbuffer.DataFrameHeader.ReservedValueFieldOffset,
+		EncodeCompressedReservedValue(codecID, length))
+
+	logbuffer.FrameLengthOrdered(appender.termBuffer, offset, frameLength)
+	return nil
+}
+
+// AppendFragmentedMessageCompressed compresses the whole payload with codec
+// and then fragments the *compressed* bytes across frames of at most
+// maxPayloadLength, so that term alignment and end-of-term padding continue
+// to operate on the bytes actually written. Every fragment carries the same
+// codec id and original length in its reserved value so a subscriber can
+// reassemble and decompress once the final fragment arrives.
+func (appender *Appender) AppendFragmentedMessageCompressed(result *AppenderResult,
+	srcBuffer *atomic.Buffer, srcOffset int32, length int32, maxPayloadLength int32, codec CompressionCodec) error {
+
+	codecID, payload, err := compressPayload(srcBuffer, srcOffset, length, codec)
+	if err != nil {
+		return err
+	}
+
+	payloadLength := int32(len(payload))
+	numMaxPayloads := payloadLength / maxPayloadLength
+	remainingPayload := payloadLength % maxPayloadLength
+	var lastFrameLength int32
+	if remainingPayload > 0 {
+		lastFrameLength = util.AlignInt32(remainingPayload+logbuffer.DataFrameHeader.Length, logbuffer.FrameAlignment)
+	}
+	requiredLength := (numMaxPayloads * (maxPayloadLength + logbuffer.DataFrameHeader.Length)) + lastFrameLength
+	rawTail := appender.getAndAddRawTail(requiredLength)
+	termOffset := rawTail & 0xFFFFFFFF
+
+	termLength := appender.termBuffer.Capacity()
+
+	result.termID = logbuffer.TermID(rawTail)
+	result.termOffset = termOffset + int64(requiredLength)
+	if result.termOffset > int64(termLength) {
+		result.termOffset = handleEndOfLogCondition(result.termID, appender.termBuffer, int32(termOffset),
+			&appender.headerWriter, termLength)
+		return nil
+	}
+
+	reservedValue := EncodeCompressedReservedValue(codecID, length)
+	flags := beginFrag
+	remaining := payloadLength
+	offset := int32(termOffset)
+
+	for remaining > 0 {
+		bytesToWrite := int32(math.Min(float64(remaining), float64(maxPayloadLength)))
+		frameLength := bytesToWrite + logbuffer.DataFrameHeader.Length
+		alignedLength := util.AlignInt32(frameLength, logbuffer.FrameAlignment)
+
+		appender.headerWriter.write(appender.termBuffer, offset, frameLength, result.termID)
+		written := payloadLength - remaining
+		appender.termBuffer.PutBytesArray(offset+logbuffer.DataFrameHeader.Length, payload[written:written+bytesToWrite])
+
+		if remaining <= maxPayloadLength {
+			flags |= endFrag
+		}
+
+		logbuffer.FrameFlags(appender.termBuffer, offset, flags)
+		appender.termBuffer.PutInt64(offset+logbuffer.DataFrameHeader.ReservedValueFieldOffset, reservedValue)
+
+		logbuffer.FrameLengthOrdered(appender.termBuffer, offset, frameLength)
+
+		flags = 0
+		offset += alignedLength
+		remaining -= bytesToWrite
+	}
+	return nil
+}
+
+// ClaimCompressed behaves like Claim but compresses srcBuffer with codec
+// first. Unlike Claim it cannot hand the caller an empty region to fill in
+// directly, since the frame length depends on the compressed size: the
+// claimed region is pre-filled with the compressed payload and left for the
+// caller to inspect or Commit.
+func (appender *Appender) ClaimCompressed(result *AppenderResult, srcBuffer *atomic.Buffer, srcOffset int32,
+	length int32, claim *logbuffer.Claim, codec CompressionCodec) error {
+
+	codecID, payload, err := compressPayload(srcBuffer, srcOffset, length, codec)
+	if err != nil {
+		return err
+	}
+
+	payloadLength := int32(len(payload))
+	frameLength := payloadLength + logbuffer.DataFrameHeader.Length
+	alignedLength := util.AlignInt32(frameLength, logbuffer.FrameAlignment)
+	rawTail := appender.getAndAddRawTail(alignedLength)
+	termOffset := rawTail & 0xFFFFFFFF
+
+	termLength := appender.termBuffer.Capacity()
+
+	result.termID = logbuffer.TermID(rawTail)
+	result.termOffset = termOffset + int64(alignedLength)
+	if result.termOffset > int64(termLength) {
+		result.termOffset = handleEndOfLogCondition(result.termID, appender.termBuffer, int32(termOffset),
+			&appender.headerWriter, termLength)
+		return nil
+	}
+
+	offset := int32(termOffset)
+	appender.headerWriter.write(appender.termBuffer, offset, frameLength, result.termID)
+	appender.termBuffer.PutBytesArray(offset+logbuffer.DataFrameHeader.Length, payload)
+	appender.termBuffer.PutInt64(offset+logbuffer.DataFrameHeader.ReservedValueFieldOffset,
+		EncodeCompressedReservedValue(codecID, length))
+	claim.Wrap(appender.termBuffer, offset, frameLength)
+	return nil
+}
+
 func handleEndOfLogCondition(termID int32, termBuffer *atomic.Buffer, termOffset int32,
 	header *headerWriter, termLength int32) int64 {
 	newOffset := AppenderFailed
@@ -0,0 +1,87 @@
+/*
+Copyright 2016 Stanislav Liberman
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package term
+
+import (
+	"github.com/lirm/aeron-go/aeron/atomic"
+	"github.com/lirm/aeron-go/aeron/logbuffer"
+	"github.com/lirm/aeron-go/aeron/util"
+)
+
+// BatchEntry identifies one message's source bytes within a call to
+// AppendBatch.
+type BatchEntry struct {
+	Buffer *atomic.Buffer
+	Offset int32
+	Length int32
+}
+
+// AppendBatch reserves space for all of msgs with a single getAndAddRawTail
+// call and writes each as its own unfragmented frame, reducing tail counter
+// contention for high-fanout publishers compared to calling
+// AppendUnfragmentedMessage once per message. If the batch would cross the
+// term boundary it is rejected in full - result.termOffset is set to
+// AppenderTripped and padding is written via handleEndOfLogCondition - rather
+// than splitting messages across terms.
+//
+// Once space is reserved, frame lengths are published in reverse order
+// (last frame first) via FrameLengthOrdered so that a subscriber never
+// observes a partially-visible batch: either none of the frames are visible
+// yet, or all of them are.
+func (appender *Appender) AppendBatch(result *AppenderResult, msgs []BatchEntry, reservedValueSupplier ReservedValueSupplier) {
+
+	frameLengths := make([]int32, len(msgs))
+	alignedLengths := make([]int32, len(msgs))
+	var requiredLength int32
+	for i, msg := range msgs {
+		frameLengths[i] = msg.Length + logbuffer.DataFrameHeader.Length
+		alignedLengths[i] = util.AlignInt32(frameLengths[i], logbuffer.FrameAlignment)
+		requiredLength += alignedLengths[i]
+	}
+
+	rawTail := appender.getAndAddRawTail(requiredLength)
+	termOffset := rawTail & 0xFFFFFFFF
+
+	termLength := appender.termBuffer.Capacity()
+
+	result.termID = logbuffer.TermID(rawTail)
+	result.termOffset = termOffset + int64(requiredLength)
+	if result.termOffset > int64(termLength) {
+		result.termOffset = handleEndOfLogCondition(result.termID, appender.termBuffer, int32(termOffset),
+			&appender.headerWriter, termLength)
+		return
+	}
+
+	offsets := make([]int32, len(msgs))
+	offset := int32(termOffset)
+	for i, msg := range msgs {
+		offsets[i] = offset
+		appender.headerWriter.write(appender.termBuffer, offset, frameLengths[i], result.termID)
+		appender.termBuffer.PutBytes(offset+logbuffer.DataFrameHeader.Length, msg.Buffer, msg.Offset, msg.Length)
+
+		if nil != reservedValueSupplier {
+			reservedValue := reservedValueSupplier(appender.termBuffer, offset, frameLengths[i])
+			appender.termBuffer.PutInt64(offset+logbuffer.DataFrameHeader.ReservedValueFieldOffset, reservedValue)
+		}
+
+		offset += alignedLengths[i]
+	}
+
+	for i := len(msgs) - 1; i >= 0; i-- {
+		logbuffer.FrameLengthOrdered(appender.termBuffer, offsets[i], frameLengths[i])
+	}
+}
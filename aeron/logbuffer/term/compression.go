@@ -0,0 +1,194 @@
+/*
+Copyright 2016 Stanislav Liberman
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package term
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec identifiers written into the top 8 bits of a compressed frame's
+// reserved value. CodecNone marks a frame that ended up uncompressed, either
+// because no codec was supplied or because compression did not shrink the
+// payload.
+const (
+	CodecNone uint8 = iota
+	CodecGzip
+	CodecSnappy
+	CodecZstd
+)
+
+// CompressionCodec compresses message payloads before they are written into
+// the term buffer. Implementations must be safe for concurrent use since a
+// single Appender may be shared by multiple publishing goroutines.
+type CompressionCodec interface {
+	// ID identifies the codec in the reserved value of a compressed frame so
+	// that a subscriber can select the matching decompressor.
+	ID() uint8
+
+	// Compress appends the compressed form of src to dst and returns the
+	// resulting slice, following the append-to-dst convention used by
+	// compress/flate and similar packages.
+	Compress(dst, src []byte) ([]byte, error)
+
+	// Decompress appends the decompressed form of src to dst and returns the
+	// resulting slice, reversing Compress. It is what lets a subscriber
+	// transparently recover the original payload instead of merely learning
+	// which codec produced it.
+	Decompress(dst, src []byte) ([]byte, error)
+}
+
+// EncodeCompressedReservedValue packs a codec id and the original,
+// uncompressed payload length into the 64-bit reserved value stored at
+// logbuffer.DataFrameHeader.ReservedValueFieldOffset: the codec id occupies
+// the top 8 bits and the uncompressed length occupies the low 32 bits.
+func EncodeCompressedReservedValue(codecID uint8, originalLength int32) int64 {
+	return int64(codecID)<<56 | int64(uint32(originalLength))
+}
+
+// DecodeCompressedReservedValue reverses EncodeCompressedReservedValue.
+func DecodeCompressedReservedValue(reservedValue int64) (codecID uint8, originalLength int32) {
+	codecID = uint8(reservedValue >> 56)
+	originalLength = int32(uint32(reservedValue))
+	return
+}
+
+// codecByID maps a codec id back to its built-in CompressionCodec, so a
+// subscriber does not have to hand-roll the id-to-decoder match itself. It
+// returns nil for CodecNone (the payload was never compressed) and for any
+// id it does not recognize.
+func codecByID(codecID uint8) CompressionCodec {
+	switch codecID {
+	case CodecGzip:
+		return GzipCodec{}
+	case CodecSnappy:
+		return SnappyCodec{}
+	case CodecZstd:
+		return ZstdCodec{}
+	default:
+		return nil
+	}
+}
+
+// DecompressReservedValue is the read-side counterpart to the
+// AppendUnfragmentedMessageCompressed/AppendFragmentedMessageCompressed/
+// ClaimCompressed family: given a frame's reserved value and payload, it
+// returns the original, uncompressed bytes, selecting the matching built-in
+// codec from the id encoded in reservedValue and passing CodecNone straight
+// through unchanged. This is what makes decompression on the read side
+// transparent rather than something every subscriber has to reimplement.
+func DecompressReservedValue(reservedValue int64, payload []byte) ([]byte, error) {
+	codecID, originalLength := DecodeCompressedReservedValue(reservedValue)
+	if codecID == CodecNone {
+		return payload, nil
+	}
+
+	codec := codecByID(codecID)
+	if codec == nil {
+		return nil, fmt.Errorf("term: unknown compression codec id %d", codecID)
+	}
+
+	return codec.Decompress(make([]byte, 0, originalLength), payload)
+}
+
+// GzipCodec compresses with the standard library's gzip implementation.
+type GzipCodec struct{}
+
+// ID implements CompressionCodec.
+func (GzipCodec) ID() uint8 { return CodecGzip }
+
+// Compress implements CompressionCodec.
+func (GzipCodec) Compress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress implements CompressionCodec.
+func (GzipCodec) Decompress(dst, src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	buf := bytes.NewBuffer(dst)
+	if _, err := io.Copy(buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SnappyCodec compresses with github.com/golang/snappy.
+type SnappyCodec struct{}
+
+// ID implements CompressionCodec.
+func (SnappyCodec) ID() uint8 { return CodecSnappy }
+
+// Compress implements CompressionCodec.
+func (SnappyCodec) Compress(dst, src []byte) ([]byte, error) {
+	return append(dst, snappy.Encode(nil, src)...), nil
+}
+
+// Decompress implements CompressionCodec.
+func (SnappyCodec) Decompress(dst, src []byte) ([]byte, error) {
+	decoded, err := snappy.Decode(nil, src)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, decoded...), nil
+}
+
+// ZstdCodec compresses with github.com/klauspost/compress/zstd. Encoders are
+// not safe to share across goroutines, so a fresh one is created per call;
+// callers appending at a high rate should wrap ZstdCodec with their own
+// encoder pool.
+type ZstdCodec struct{}
+
+// ID implements CompressionCodec.
+func (ZstdCodec) ID() uint8 { return CodecZstd }
+
+// Compress implements CompressionCodec.
+func (ZstdCodec) Compress(dst, src []byte) ([]byte, error) {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer enc.Close()
+	return enc.EncodeAll(src, dst), nil
+}
+
+// Decompress implements CompressionCodec.
+func (ZstdCodec) Decompress(dst, src []byte) ([]byte, error) {
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+	return dec.DecodeAll(src, dst)
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2016 Stanislav Liberman
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package term
+
+import "github.com/lirm/aeron-go/aeron/logbuffer"
+
+// TailLockManager optionally serializes the getAndAddRawTail step across
+// independent processes that have the same log buffer file mapped.
+// getAndAddRawTail is otherwise a single lock-free RMW on tailCounter, which
+// is safe across goroutines/threads within one process (the atomic CAS is
+// cache-coherent) but not across processes unless every writer agrees on a
+// lock, since nothing stops two processes from computing the same raw tail
+// from two stale reads.
+type TailLockManager interface {
+	// Lock acquires the lock for (streamID, sessionID) before a tail
+	// mutation. It must be paired with a call to Unlock.
+	Lock(streamID, sessionID int32) error
+
+	// Unlock releases a lock acquired by Lock.
+	Unlock(streamID, sessionID int32) error
+
+	// Close releases any resources this manager holds for the appender it
+	// was attached to, including the slot for any (streamID, sessionID)
+	// pair it has ever locked.
+	Close() error
+}
+
+// NoopTailLockManager preserves today's lock-free, single-process atomic
+// behaviour. It is the default for every Appender created via MakeAppender.
+type NoopTailLockManager struct{}
+
+// Lock implements TailLockManager.
+func (NoopTailLockManager) Lock(streamID, sessionID int32) error { return nil }
+
+// Unlock implements TailLockManager.
+func (NoopTailLockManager) Unlock(streamID, sessionID int32) error { return nil }
+
+// Close implements TailLockManager.
+func (NoopTailLockManager) Close() error { return nil }
+
+// MakeAppenderWithLock is a factory function like MakeAppender that attaches
+// lockManager to the returned Appender so that getAndAddRawTail acquires a
+// lock for (streamID, sessionID) before mutating tailCounter and releases it
+// afterwards. Pass NoopTailLockManager{} (or use MakeAppender) to keep the
+// existing lock-free behaviour for single-process use.
+func MakeAppenderWithLock(logBuffers *logbuffer.LogBuffers, partitionIndex int, lockManager TailLockManager) *Appender {
+	appender := MakeAppender(logBuffers, partitionIndex)
+	appender.lockManager = lockManager
+	return appender
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2016 Stanislav Liberman
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package term
+
+import (
+	"fmt"
+
+	"github.com/lirm/aeron-go/aeron/atomic"
+)
+
+// traceContextFlag is set on a frame's FlagsFieldOffset to indicate that its
+// reserved value holds a trace context encoded by EncodeTraceContext rather
+// than an application-defined reserved value. It is chosen clear of
+// beginFrag/endFrag so a fragmented message can carry both.
+const traceContextFlag uint8 = 0x20
+
+// TraceContextSupplier returns a compact correlation tag, encoded as an
+// 8-byte reserved value via EncodeTraceContext, that should be attached to
+// the frame about to be written. It has the same shape as
+// ReservedValueSupplier so a caller can use either interchangeably, or layer
+// a TraceContextSupplier on top of an existing ReservedValueSupplier.
+//
+// The tag is lossy: it folds a trace id down to 31 bits (see
+// EncodeTraceContext), so it is only useful to notice that two frames were
+// published as part of the same trace within a single process's
+// lifetime - two unrelated traces can collide into the same tag, and the
+// fold cannot be reversed back into the original trace id to look it up in
+// a tracing backend (Jaeger, Zipkin, Tempo, ...). Propagate the real,
+// full-width trace and span ids out of band (e.g. in the message payload,
+// or via your own higher-width framing) if a subscriber needs to join
+// frames back to spans in a tracing backend.
+type TraceContextSupplier func(termBuffer *atomic.Buffer, termOffset int32, length int32) int64
+
+// EncodeTraceContext packs a folded trace id, span id and sampled flag into
+// the 64-bit reserved value stored at
+// logbuffer.DataFrameHeader.ReservedValueFieldOffset: bit 63 is the sampled
+// flag, bits 32-62 hold the folded trace id and bits 0-31 hold the folded
+// span id. Callers propagating full 128-bit OpenTelemetry trace ids must
+// fold them down first (e.g. via the low 31 bits of an FNV hash); see the
+// TraceContextSupplier doc comment for what that fold costs.
+func EncodeTraceContext(traceID uint32, spanID uint32, sampled bool) int64 {
+	var sampledBit int64
+	if sampled {
+		sampledBit = 1 << 63
+	}
+	return sampledBit | int64(traceID&0x7FFFFFFF)<<32 | int64(spanID)
+}
+
+// DecodeTraceContext reverses EncodeTraceContext.
+func DecodeTraceContext(reservedValue int64) (traceID uint32, spanID uint32, sampled bool) {
+	sampled = reservedValue&(1<<63) != 0
+	traceID = uint32(reservedValue>>32) & 0x7FFFFFFF
+	spanID = uint32(reservedValue)
+	return
+}
+
+// IsTraceContext reports whether flags, as written to a frame's
+// FlagsFieldOffset, indicate that the frame's reserved value is a trace
+// context rather than an application-defined reserved value.
+func IsTraceContext(flags uint8) bool {
+	return flags&traceContextFlag == traceContextFlag
+}
+
+// fullTraceHeaderFlag is set on a frame's FlagsFieldOffset to indicate that
+// its payload begins with a TraceHeaderLength-byte header encoded by
+// EncodeTraceHeader, ahead of the application payload. It is distinct from
+// traceContextFlag: that flag repurposes the reserved value, this one
+// reshapes the payload, and a frame never needs both at once.
+const fullTraceHeaderFlag uint8 = 0x10
+
+// TraceHeaderLength is the fixed size, in bytes, of the header
+// EncodeTraceHeader produces: 16 bytes of trace id, 8 bytes of span id and
+// 1 byte of sampled flag, padded out to a round size.
+const TraceHeaderLength = 32
+
+// FullTraceContext is a full-width, W3C/OpenTelemetry-shaped trace context:
+// a 128-bit trace id and a 64-bit span id, carried whole rather than folded.
+// Unlike the reserved-value encoding in EncodeTraceContext, a
+// FullTraceContext round-trips back to the exact ids a tracing backend
+// (Jaeger, Zipkin, Tempo, ...) recorded the span under, at the cost of
+// consuming payload bytes instead of just the reserved value.
+type FullTraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// EncodeTraceHeader serializes ctx into the fixed TraceHeaderLength-byte
+// header that AppendUnfragmentedMessageWithFullTrace prepends to a message's
+// payload.
+func EncodeTraceHeader(ctx FullTraceContext) [TraceHeaderLength]byte {
+	var header [TraceHeaderLength]byte
+	copy(header[0:16], ctx.TraceID[:])
+	copy(header[16:24], ctx.SpanID[:])
+	if ctx.Sampled {
+		header[24] = 1
+	}
+	return header
+}
+
+// DecodeTraceHeader reverses EncodeTraceHeader. It returns an error if
+// header is shorter than TraceHeaderLength rather than reading out of
+// bounds.
+func DecodeTraceHeader(header []byte) (FullTraceContext, error) {
+	var ctx FullTraceContext
+	if len(header) < TraceHeaderLength {
+		return ctx, fmt.Errorf("term: trace header too short: got %d bytes, want %d", len(header), TraceHeaderLength)
+	}
+
+	copy(ctx.TraceID[:], header[0:16])
+	copy(ctx.SpanID[:], header[16:24])
+	ctx.Sampled = header[24] != 0
+	return ctx, nil
+}
+
+// IsFullTraceHeader reports whether flags, as written to a frame's
+// FlagsFieldOffset, indicate that the frame's payload begins with a
+// TraceHeaderLength-byte header produced by EncodeTraceHeader.
+func IsFullTraceHeader(flags uint8) bool {
+	return flags&fullTraceHeaderFlag == fullTraceHeaderFlag
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2016 Stanislav Liberman
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package otel adapts OpenTelemetry spans to Aeron frame trace metadata, in
+// two widths. SpanContextSupplier produces a compact correlation tag carried
+// in the frame's reserved value (see term.TraceContextSupplier); it only
+// tells a subscriber that two frames were published as part of the same
+// trace in this process; it is not wide enough to carry, and cannot be
+// reversed back into, the original 128-bit trace id or 64-bit span id, so it
+// cannot be used to look a trace up in a backend like Jaeger, Zipkin or
+// Tempo. FullSpanContext instead builds a term.FullTraceContext carrying the
+// real, un-folded ids for use with
+// term.Appender.AppendUnfragmentedMessageWithFullTrace, which is what to
+// reach for when a subscriber does need backend correlation.
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lirm/aeron-go/aeron/atomic"
+	"github.com/lirm/aeron-go/aeron/logbuffer/term"
+)
+
+// hashTraceID folds a 128-bit OpenTelemetry trace id down to the 31 bits
+// EncodeTraceContext has room for. The fold is one-way: it cannot be
+// reversed back into traceID.
+func hashTraceID(traceID trace.TraceID) uint32 {
+	var h uint32 = 2166136261
+	for _, b := range traceID {
+		h = (h ^ uint32(b)) * 16777619
+	}
+	return h & 0x7FFFFFFF
+}
+
+// SpanContextSupplier returns a term.TraceContextSupplier that reads the
+// span recorded on ctx and encodes its folded correlation tag into the
+// reserved value of the frame being appended. It returns nil when ctx
+// carries no valid span, in which case the term.Appender ...WithTrace
+// methods neither write a trace context nor set the trace context flag.
+func SpanContextSupplier(ctx context.Context) term.TraceContextSupplier {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	traceID := hashTraceID(sc.TraceID())
+	spanID := sc.SpanID()
+	var spanID32 uint32
+	for _, b := range spanID {
+		spanID32 = spanID32<<8 | uint32(b)
+	}
+
+	return func(termBuffer *atomic.Buffer, termOffset int32, length int32) int64 {
+		return term.EncodeTraceContext(traceID, spanID32, sc.IsSampled())
+	}
+}
+
+// ExtractCorrelationTag decodes a reserved value previously produced by
+// SpanContextSupplier back into its folded trace id, folded span id and
+// sampled flag. Because the fold is one-way and collision-prone, the result
+// can only be used to notice that two frames share a trace within this
+// process; it is not the original OpenTelemetry trace.TraceID/SpanID and
+// cannot be used to query a tracing backend.
+func ExtractCorrelationTag(reservedValue int64) (traceID uint32, spanID uint32, sampled bool) {
+	return term.DecodeTraceContext(reservedValue)
+}
+
+// FullSpanContext builds a term.FullTraceContext carrying ctx's real,
+// un-folded trace id, span id and sampled flag, for use with
+// term.Appender.AppendUnfragmentedMessageWithFullTrace. The second return
+// value is false (and the FullTraceContext zero) when ctx carries no valid
+// span.
+func FullSpanContext(ctx context.Context) (term.FullTraceContext, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return term.FullTraceContext{}, false
+	}
+
+	return term.FullTraceContext{
+		TraceID: sc.TraceID(),
+		SpanID:  sc.SpanID(),
+		Sampled: sc.IsSampled(),
+	}, true
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2016 Stanislav Liberman
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package zipkin adapts OpenTracing/Zipkin spans to Aeron frame trace
+// metadata, in two widths, mirroring package otel for services instrumented
+// with github.com/openzipkin/zipkin-go instead of OpenTelemetry.
+// SpanContextSupplier produces a compact correlation tag carried in the
+// frame's reserved value (see term.TraceContextSupplier); the tag only
+// tells a subscriber that two frames were published as part of the same
+// trace in this process, it is too narrow to carry, and cannot be reversed
+// back into, the original 128-bit trace id, so it cannot be used to look a
+// trace up in the Zipkin UI or backend. FullSpanContext instead builds a
+// term.FullTraceContext carrying the real, un-folded ids for use with
+// term.Appender.AppendUnfragmentedMessageWithFullTrace, which is what to
+// reach for when a subscriber does need backend correlation.
+package zipkin
+
+import (
+	"encoding/binary"
+
+	model "github.com/openzipkin/zipkin-go/model"
+
+	"github.com/lirm/aeron-go/aeron/atomic"
+	"github.com/lirm/aeron-go/aeron/logbuffer/term"
+)
+
+// hashTraceID folds a Zipkin 128-bit trace id down to the 31 bits
+// EncodeTraceContext has room for. The fold is one-way: it cannot be
+// reversed back into traceID.
+func hashTraceID(traceID model.TraceID) uint32 {
+	return uint32(traceID.Low) & 0x7FFFFFFF
+}
+
+// SpanContextSupplier returns a term.TraceContextSupplier that encodes
+// span's folded correlation tag into the reserved value of the frame being
+// appended.
+func SpanContextSupplier(span model.SpanContext) term.TraceContextSupplier {
+	traceID := hashTraceID(span.TraceID)
+	spanID := uint32(span.ID)
+	sampled := span.Sampled != nil && *span.Sampled
+
+	return func(termBuffer *atomic.Buffer, termOffset int32, length int32) int64 {
+		return term.EncodeTraceContext(traceID, spanID, sampled)
+	}
+}
+
+// ExtractCorrelationTag decodes the folded trace id, folded span id and
+// sampled flag from a reserved value previously produced by
+// SpanContextSupplier. Because the fold is one-way and collision-prone, the
+// result can only be used to notice that two frames share a trace within
+// this process; it is not the original Zipkin trace/span id and cannot be
+// used to query Zipkin.
+func ExtractCorrelationTag(reservedValue int64) (traceID uint32, spanID uint32, sampled bool) {
+	return term.DecodeTraceContext(reservedValue)
+}
+
+// FullSpanContext builds a term.FullTraceContext carrying span's real,
+// un-folded 128-bit trace id and 64-bit span id, for use with
+// term.Appender.AppendUnfragmentedMessageWithFullTrace.
+func FullSpanContext(span model.SpanContext) term.FullTraceContext {
+	var traceID [16]byte
+	binary.BigEndian.PutUint64(traceID[0:8], span.TraceID.High)
+	binary.BigEndian.PutUint64(traceID[8:16], span.TraceID.Low)
+
+	var spanID [8]byte
+	binary.BigEndian.PutUint64(spanID[:], uint64(span.ID))
+
+	return term.FullTraceContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: span.Sampled != nil && *span.Sampled,
+	}
+}